@@ -4,25 +4,31 @@ package main
 // Dependencies
 //
 import (
+    "context"
     "encoding/json"
     "fmt"
+    "log"
     "os"
     "strings"
-    "strconv"
-    "regexp"
-    "log"
-    "github.com/scaleway/go-scaleway"
-    "github.com/scaleway/go-scaleway/logger"
-    "github.com/scaleway/go-scaleway/types"
+    "time"
+
+    "github.com/AlbanMontaigu/scaleway-dynamic-inventory/pkg/inventory"
+    "github.com/scaleway/scaleway-sdk-go/api/instance/v1"
+    "github.com/scaleway/scaleway-sdk-go/scw"
 )
 
 //
 // Global variables
 //
 var (
-    scwApi api.ScalewayAPI
+    // scwApi/scwClient back the spawn/run/destroy provisioning subcommands,
+    // which sit outside the pkg/inventory Client
+    scwClient *scw.Client
+    scwApi *instance.API
+
+    refreshCache bool
+
     l = log.New(os.Stderr, "", 0)
-    allowedServerName = regexp.MustCompile("proxy[0-9]|master[[0-9]|worker+[0-9]")
 )
 
 //
@@ -30,198 +36,262 @@ var (
 //
 const (
     MSG_PREFIX = "scw-inv:"
+    defaultTimeout = 30 * time.Second
 )
 
 //
-// Main function
+// Main function. This is a thin CLI wrapper: all the inventory logic lives
+// in pkg/inventory so it can be imported and tested without os.Exit.
 //
 func main() {
 
-    // Init API
-    initScwApi()
+    // Parse CLI flags (--list / --host <name> / --config <path> / --refresh-cache
+    // / spawn|run|destroy <manifest>)
+    action, hostName, configPath, manifestPath := parseArgs(os.Args)
 
-    // Vars
-    var jsonResponse []byte
-    var err error
+    // Fleet lifecycle subcommands don't produce an inventory, they mutate it
+    switch action {
+    case "spawn", "run", "destroy":
+        initScwApiForProvisioning()
+        if manifestPath == "" {
+            l.Printf("%s %s requires a manifest path (%s <manifest>)", MSG_PREFIX, action, action)
+            os.Exit(1)
+        }
+        if err := runProvisioning(action, manifestPath); err != nil {
+            l.Printf("%s %s failed: %s", MSG_PREFIX, action, err)
+            os.Exit(1)
+        }
+        return
+    }
 
-    // Handling default value
-    osArg1 := "--list"
-    if len(os.Args) > 1 {
-        osArg1 = os.Args[1]
+    client, err := newInventoryClient(configPath)
+    if err != nil {
+        l.Printf("%s %s", MSG_PREFIX, err)
+        os.Exit(1)
     }
 
+    ctx, cancel := context.WithTimeout(context.Background(), scwTimeout())
+    defer cancel()
+
+    // Vars
+    var jsonResponse []byte
+
     // Process depending the flags
-    switch osArg1 {
-    
+    switch action {
+
     // Get server list
     case "--list":
-        jsonResponse, err = json.Marshal(getServers())
-            
+        inv, err := client.List(ctx)
+        if err != nil {
+            l.Printf("%s failed to list servers: %s", MSG_PREFIX, err)
+            os.Exit(1)
+        }
+        jsonResponse, err = json.Marshal(inv)
+        if err != nil {
+            l.Printf("%s failed to marshal the dynamic inventory: %s", MSG_PREFIX, err)
+            os.Exit(1)
+        }
+
     // Get server details
     case "--host":
-        if len(os.Args) < 2  {
+        if hostName == "" {
             l.Printf("%s hostname is required (--host <hostname>)", MSG_PREFIX)
             os.Exit(1)
         }
-        jsonResponse, err = json.Marshal(getServer(os.Args[2]))
-        
+        vars, err := client.Host(ctx, hostName)
+        if err != nil {
+            l.Printf("%s failed to get host %s: %s", MSG_PREFIX, hostName, err)
+            os.Exit(1)
+        }
+        jsonResponse, err = json.Marshal(vars)
+        if err != nil {
+            l.Printf("%s failed to marshal the dynamic inventory: %s", MSG_PREFIX, err)
+            os.Exit(1)
+        }
+
     // No arg so do nothing exit directly
     default:
-        l.Printf("%s usage: [--host|--list]", MSG_PREFIX)
+        l.Printf("%s usage: [--host <hostname>|--list] [--config <path>] [--refresh-cache]", MSG_PREFIX)
+        l.Printf("%s        spawn|run|destroy <manifest>", MSG_PREFIX)
         os.Exit(1)
     }
 
-    // Cherck result and displays it if any
-    if err != nil {
-        l.Printf("%s failed to marshal the dynamic inventory: %s", MSG_PREFIX, err)
-        os.Exit(1)
-    }
     fmt.Println(string(jsonResponse))
 }
 
 //
-// Initialize the common scaleway API object
+// Parse the CLI arguments, pulling the --config flag out of whichever
+// position it was given alongside --list / --host, or recognizing the
+// spawn/run/destroy provisioning subcommands and their manifest path
 //
-func initScwApi () {
+func parseArgs(args []string) (action string, hostName string, configPath string, manifestPath string) {
+    action = "--list"
 
-    // Get and control scaleway tokens
-    scwOrga := strings.TrimSpace(os.Getenv("SCALEWAY_ORGANIZATION"))
-    if strings.TrimSpace(scwOrga) == ""  {
-        l.Printf("%s required SCALEWAY_ORGANIZATION env var is not set", MSG_PREFIX)
-        os.Exit(1)
-    }
-    scwToken := strings.TrimSpace(os.Getenv("SCALEWAY_TOKEN"))
-    if strings.TrimSpace(scwToken) == "" {
-        l.Printf("%s required SCALEWAY_TOKEN env var is not set", MSG_PREFIX)
-        os.Exit(1)
+    rest := args[1:]
+    if len(rest) > 0 {
+        switch rest[0] {
+        case "spawn", "run", "destroy":
+            action = rest[0]
+            if len(rest) > 1 {
+                manifestPath = rest[1]
+            }
+            return
+        }
     }
 
-    // Init api object
-    disabledLoggerFunc := func(a *api.ScalewayAPI) {
-        a.Logger = logger.NewDisableLogger()
-    }
-    api, err := api.NewScalewayAPI(scwOrga, scwToken, "Scaleway Dynamic Inventory", "", disabledLoggerFunc)
-    if err != nil {
-        l.Printf("%s failed to create scaleway API instance: %s", MSG_PREFIX, err)
-        os.Exit(1)
+    for i := 0; i < len(rest); i++ {
+        switch rest[i] {
+        case "--config":
+            if i+1 < len(rest) {
+                configPath = rest[i+1]
+                i++
+            }
+        case "--host":
+            action = "--host"
+            if i+1 < len(rest) {
+                hostName = rest[i+1]
+                i++
+            }
+        case "--list":
+            action = "--list"
+        case "--refresh-cache":
+            refreshCache = true
+        }
     }
-    scwApi = *api
+    return
 }
 
 //
-// Get servers list (--list flag)
+// newInventoryClient builds a pkg/inventory.Client from the process's env
+// vars and CLI flags
 //
-func getServers() map[string][]string {
+func newInventoryClient(configPath string) (*inventory.Client, error) {
 
-    // API call
-    servers, err := scwApi.GetServers(true, 0)
-    if err != nil {
-        l.Printf("%s failed to get servers: %s", MSG_PREFIX, err)
-        os.Exit(1)
+    scwOrga := strings.TrimSpace(os.Getenv("SCALEWAY_ORGANIZATION"))
+    if scwOrga == "" {
+        return nil, fmt.Errorf("required SCALEWAY_ORGANIZATION env var is not set")
+    }
+    scwAccessKey := strings.TrimSpace(os.Getenv("SCALEWAY_ACCESS_KEY"))
+    if scwAccessKey == "" {
+        return nil, fmt.Errorf("required SCALEWAY_ACCESS_KEY env var is not set")
+    }
+    scwToken := strings.TrimSpace(os.Getenv("SCALEWAY_TOKEN"))
+    if scwToken == "" {
+        return nil, fmt.Errorf("required SCALEWAY_TOKEN env var is not set")
     }
 
-    // Prepare result
-    result := make(map[string][]string)
+    if configPath == "" {
+        configPath = strings.TrimSpace(os.Getenv("SCW_INVENTORY_CONFIG"))
+    }
+    cfg := inventory.DefaultConfig()
+    if configPath != "" {
+        loaded, err := inventory.LoadConfig(configPath)
+        if err != nil {
+            return nil, fmt.Errorf("failed to load inventory config %s: %w", configPath, err)
+        }
+        cfg = loaded
+    }
 
-    // Build result
-    for _, server := range *servers {
+    return inventory.NewClient(inventory.ClientConfig{
+        Organization: scwOrga,
+        AccessKey:    scwAccessKey,
+        Token:        scwToken,
+        Zones:        parseScwZones(os.Getenv("SCALEWAY_ZONES")),
+        Inventory:    cfg,
+        CacheTTL:     cacheTTL(),
+        RefreshCache: refreshCache,
+    })
+}
 
-        // Servers to filter / skip in the loop
-        if (!allowedServerName.MatchString(server.Name)) {
+//
+// Parse SCALEWAY_ZONES into a list of scw.Zone, defaulting to inventory.DefaultZone
+//
+func parseScwZones(raw string) []scw.Zone {
+    raw = strings.TrimSpace(raw)
+    if raw == "" {
+        return []scw.Zone{scw.Zone(inventory.DefaultZone)}
+    }
+    var zones []scw.Zone
+    for _, z := range strings.Split(raw, ",") {
+        z = strings.TrimSpace(z)
+        if z == "" {
             continue
         }
-
-        // Servers not filtered, adding it to the result
-        for _, tag := range server.Tags {
-            if _, ok := result[tag]; !ok {
-                result[tag] = make([]string, 0)
-            }
-            result[tag] = append(result[tag], server.Name)
-        }
+        zones = append(zones, scw.Zone(z))
+    }
+    if len(zones) == 0 {
+        return []scw.Zone{scw.Zone(inventory.DefaultZone)}
     }
-    return result
+    return zones
 }
 
 //
-// Get server by name (scw whants id)
+// cacheTTL reads SCW_CACHE_TTL (a time.Duration string, e.g. "5m"), falling
+// back to inventory.DefaultCacheTTL
 //
-func getScWServerByName(serverName string) *types.ScalewayServer {
-
-    // API call
-    serverId, err := scwApi.GetServerID(serverName)
+func cacheTTL() time.Duration {
+    raw := strings.TrimSpace(os.Getenv("SCW_CACHE_TTL"))
+    if raw == "" {
+        return inventory.DefaultCacheTTL
+    }
+    ttl, err := time.ParseDuration(raw)
     if err != nil {
-        l.Printf("%s failed to get server id with name: %s", MSG_PREFIX, err)
-        os.Exit(1)
+        l.Printf("%s invalid SCW_CACHE_TTL %q, using default of %s: %s", MSG_PREFIX, raw, inventory.DefaultCacheTTL, err)
+        return inventory.DefaultCacheTTL
+    }
+    return ttl
+}
+
+//
+// scwTimeout reads SCW_TIMEOUT (a time.Duration string, e.g. "30s"), falling
+// back to defaultTimeout
+//
+func scwTimeout() time.Duration {
+    raw := strings.TrimSpace(os.Getenv("SCW_TIMEOUT"))
+    if raw == "" {
+        return defaultTimeout
     }
-    server, err := scwApi.GetServer(serverId)
+    timeout, err := time.ParseDuration(raw)
     if err != nil {
-        l.Printf("%s failed to get server with id: %s", MSG_PREFIX, err)
-        os.Exit(1)
+        l.Printf("%s invalid SCW_TIMEOUT %q, using default of %s: %s", MSG_PREFIX, raw, defaultTimeout, err)
+        return defaultTimeout
     }
-    return server
+    return timeout
 }
 
 //
-// Get server details (with --host flag)
+// initScwApiForProvisioning sets up the raw scwApi/scwClient used by the
+// spawn/run/destroy subcommands in provision.go
 //
-func getServer(serverName string) map[string]string {
+func initScwApiForProvisioning() {
 
-    // Servers to filter / skip in the loop
-    if (!allowedServerName.MatchString(serverName)) {
-        l.Printf("%s server name allowed regexp: %s", MSG_PREFIX, allowedServerName.String())
+    scwOrga := strings.TrimSpace(os.Getenv("SCALEWAY_ORGANIZATION"))
+    if scwOrga == "" {
+        l.Printf("%s required SCALEWAY_ORGANIZATION env var is not set", MSG_PREFIX)
         os.Exit(1)
     }
-
-    // Prepare targeted server
-    var server *types.ScalewayServer
-
-    // Prepare result
-    result := make(map[string]string)
-
-    // Build generic stuff
-    result["ansible_python_interpreter"] = "/usr/bin/python3"
-    result["ansible_user"] = "root"
-
-    // Get proxy0 public ip for gateway
-    serverProxy0 := getScWServerByName("proxy0")
-
-    // Common proxy conf
-    result["ansible_ssh_common_args"] = "-q -C -o ControlMaster=auto -o ControlPersist=5m -o ForwardAgent=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
-
-    // Build specific result for proxy0
-    if serverName == "proxy0" {
-        server = serverProxy0
-        result["proxy_inet"] = "True"
-    } else {
-        server = getScWServerByName(serverName)
-        result["ansible_ssh_common_args"] = result["ansible_ssh_common_args"] + " -o ProxyCommand=\"ssh -W %h:%p -q root@" + serverProxy0.PublicAddress.IP + " -i ~/.ssh/scaleway.pem\""
-    }
-
-    // Build ansible hosts and takes care about public / private ip
-    if server.PublicAddress.IP != "" {
-        result["ansible_host"] = server.PublicAddress.IP
-    } else {
-        result["ansible_host"] = server.PrivateIP
-    }
-
-    // Build the vpn_ip
-    digitPos := len(server.Name)-1
-    if digitPos > 1 {
-        lastDigit := string(server.Name[digitPos:])
-        if _, err := strconv.Atoi(lastDigit); err == nil {
-            switch {
-                case strings.Contains(server.Name, "proxy"):
-                    result["vpn_ip"] = "192.168.66.1" + lastDigit
-                    
-                case strings.Contains(server.Name, "master"):
-                    result["vpn_ip"] = "192.168.66.2" + lastDigit
-                    
-                case strings.Contains(server.Name, "worker"):
-                    result["vpn_ip"] = "192.168.66.3" + lastDigit
-                    
-            }
-        }
+    scwAccessKey := strings.TrimSpace(os.Getenv("SCALEWAY_ACCESS_KEY"))
+    if scwAccessKey == "" {
+        l.Printf("%s required SCALEWAY_ACCESS_KEY env var is not set", MSG_PREFIX)
+        os.Exit(1)
+    }
+    scwToken := strings.TrimSpace(os.Getenv("SCALEWAY_TOKEN"))
+    if scwToken == "" {
+        l.Printf("%s required SCALEWAY_TOKEN env var is not set", MSG_PREFIX)
+        os.Exit(1)
     }
-    return result
 
+    zones := parseScwZones(os.Getenv("SCALEWAY_ZONES"))
+
+    client, err := scw.NewClient(
+        scw.WithAuth(scwAccessKey, scwToken),
+        scw.WithDefaultOrganizationID(scwOrga),
+        scw.WithDefaultZone(zones[0]),
+    )
+    if err != nil {
+        l.Printf("%s failed to create scaleway client: %s", MSG_PREFIX, err)
+        os.Exit(1)
+    }
+    scwClient = client
+    scwApi = instance.NewAPI(scwClient)
 }
@@ -0,0 +1,165 @@
+package inventory
+
+//
+// Dependencies
+//
+import (
+    "crypto/sha1"
+    "encoding/hex"
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/scaleway/scaleway-sdk-go/api/instance/v1"
+)
+
+//
+// Global constants
+//
+const (
+    cacheDirName      = "scaleway-dynamic-inventory"
+    DefaultCacheTTL   = 5 * time.Minute
+)
+
+//
+// cacheFile is the on-disk representation of a single cached ListServers
+// pass, shared by List and Host to turn N+1 API calls into one
+//
+type cacheFile struct {
+    FetchedAt time.Time `json:"fetched_at"`
+    Servers   []*instance.Server `json:"servers"`
+}
+
+//
+// cacheDir resolves $XDG_CACHE_HOME/scaleway-dynamic-inventory, falling back
+// to ~/.cache/scaleway-dynamic-inventory, creating it if needed
+//
+func cacheDir() (string, error) {
+    base := strings.TrimSpace(os.Getenv("XDG_CACHE_HOME"))
+    if base == "" {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return "", err
+        }
+        base = filepath.Join(home, ".cache")
+    }
+    dir := filepath.Join(base, cacheDirName)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return "", err
+    }
+    return dir, nil
+}
+
+//
+// cachePath returns the cache file for this client's organization and zone
+// set, so distinct fleets never share a cache entry
+//
+func (c *Client) cachePath() (string, error) {
+    dir, err := cacheDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, c.cacheKey()+".json"), nil
+}
+
+//
+// cacheKey hashes the organization and sorted zone list into a stable,
+// filesystem-safe cache file name
+//
+func (c *Client) cacheKey() string {
+    parts := make([]string, len(c.zones))
+    for i, zone := range c.zones {
+        parts[i] = zone.String()
+    }
+    sort.Strings(parts)
+    raw := c.orga + "|" + strings.Join(parts, ",")
+    sum := sha1.Sum([]byte(raw))
+    return hex.EncodeToString(sum[:])
+}
+
+//
+// loadCache reads and flock(2)-shared-locks the cache file, returning
+// false if it is missing, corrupt, or older than the client's cache TTL
+//
+func loadCache(path string, ttl time.Duration) (*cacheFile, bool) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, false
+    }
+    defer f.Close()
+
+    if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+        return nil, false
+    }
+    defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+    data, err := ioutil.ReadAll(f)
+    if err != nil {
+        return nil, false
+    }
+
+    var entry cacheFile
+    if err := json.Unmarshal(data, &entry); err != nil {
+        return nil, false
+    }
+
+    if time.Since(entry.FetchedAt) > ttl {
+        return nil, false
+    }
+    return &entry, true
+}
+
+//
+// writeCacheFile marshals servers and atomically replaces path via a
+// temp file + rename. Callers are responsible for holding the lockfile.
+//
+func writeCacheFile(path string, servers []*instance.Server) error {
+    data, err := json.Marshal(cacheFile{FetchedAt: time.Now(), Servers: servers})
+    if err != nil {
+        return err
+    }
+
+    tmp := path + ".tmp"
+    if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+//
+// fetchWithCache flock(2)-exclusive-locks a lockfile next to the cache and,
+// holding that lock, re-checks freshness before calling fetch. This closes
+// the race where N concurrent Ansible workers all see a stale/missing cache
+// and independently stampede the Scaleway API: losers of the race block on
+// the lock and then read the winner's freshly written file instead.
+//
+func fetchWithCache(path string, ttl time.Duration, refresh bool, fetch func() ([]*instance.Server, error)) ([]*instance.Server, error) {
+    lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+    if err != nil {
+        return fetch()
+    }
+    defer lock.Close()
+
+    if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+        return fetch()
+    }
+    defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+    if !refresh {
+        if entry, ok := loadCache(path, ttl); ok {
+            return entry.Servers, nil
+        }
+    }
+
+    servers, err := fetch()
+    if err != nil {
+        return nil, err
+    }
+    _ = writeCacheFile(path, servers)
+    return servers, nil
+}
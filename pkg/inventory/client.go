@@ -0,0 +1,342 @@
+package inventory
+
+//
+// Dependencies
+//
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/scaleway/scaleway-sdk-go/api/instance/v1"
+    "github.com/scaleway/scaleway-sdk-go/scw"
+)
+
+//
+// Global constants
+//
+const (
+    DefaultZone = "fr-par-1"
+)
+
+//
+// HostVars is the set of Ansible variables computed for a single host
+//
+type HostVars map[string]string
+
+//
+// Inventory is the Ansible dynamic-inventory shape returned by List:
+// one entry per group plus a top-level "_meta.hostvars" block.
+//
+type Inventory map[string]interface{}
+
+//
+// ansibleGroup is the per-group shape of the Ansible dynamic inventory spec
+//
+type ansibleGroup struct {
+    Hosts []string `json:"hosts"`
+}
+
+//
+// ClientConfig configures a Client. Organization, Token and Zones mirror
+// SCALEWAY_ORGANIZATION / SCALEWAY_TOKEN / SCALEWAY_ZONES; Inventory is the
+// grouping/filtering config loaded via LoadConfig or DefaultConfig.
+//
+type ClientConfig struct {
+    Organization string
+    // AccessKey is the Scaleway access key (SCWXXXXXXXXXXXXXXXXX), used as
+    // the scw.WithAuth access key alongside Token as the secret key.
+    AccessKey    string
+    Token        string
+    Zones        []scw.Zone
+    Inventory    *Config
+    CacheTTL     time.Duration
+    RefreshCache bool
+
+    // APIURL overrides the Scaleway API endpoint. Only meant for tests.
+    APIURL string
+}
+
+//
+// Client is a thin, importable wrapper around the Scaleway instance API
+// that powers both the Ansible inventory and the CLI.
+//
+type Client struct {
+    api          *instance.API
+    orga         string
+    zones        []scw.Zone
+    config       *Config
+    cacheTTL     time.Duration
+    refreshCache bool
+}
+
+//
+// NewClient validates cc and builds a ready-to-use Client
+//
+func NewClient(cc ClientConfig) (*Client, error) {
+    if cc.Organization == "" {
+        return nil, fmt.Errorf("inventory: %w: organization is required", ErrAuth)
+    }
+    if cc.AccessKey == "" {
+        return nil, fmt.Errorf("inventory: %w: access key is required", ErrAuth)
+    }
+    if cc.Token == "" {
+        return nil, fmt.Errorf("inventory: %w: token is required", ErrAuth)
+    }
+    if len(cc.Zones) == 0 {
+        cc.Zones = []scw.Zone{scw.Zone(DefaultZone)}
+    }
+    if cc.Inventory == nil {
+        cc.Inventory = DefaultConfig()
+    }
+    if err := compileConfig(cc.Inventory); err != nil {
+        return nil, fmt.Errorf("inventory: invalid config: %w", err)
+    }
+    if cc.CacheTTL == 0 {
+        cc.CacheTTL = DefaultCacheTTL
+    }
+
+    opts := []scw.ClientOption{
+        scw.WithAuth(cc.AccessKey, cc.Token),
+        scw.WithDefaultOrganizationID(cc.Organization),
+        scw.WithDefaultZone(cc.Zones[0]),
+    }
+    if cc.APIURL != "" {
+        opts = append(opts, scw.WithAPIURL(cc.APIURL))
+    }
+
+    scwClient, err := scw.NewClient(opts...)
+    if err != nil {
+        return nil, fmt.Errorf("inventory: failed to create scaleway client: %w", err)
+    }
+
+    return &Client{
+        api:          instance.NewAPI(scwClient),
+        orga:         cc.Organization,
+        zones:        cc.Zones,
+        config:       cc.Inventory,
+        cacheTTL:     cc.CacheTTL,
+        refreshCache: cc.RefreshCache,
+    }, nil
+}
+
+//
+// List builds the full Ansible inventory: one entry per group plus a
+// top-level _meta.hostvars block computed for every host in a single pass.
+//
+func (c *Client) List(ctx context.Context) (Inventory, error) {
+    servers, err := c.servers(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    bastion, err := c.findBastion(servers)
+    if err != nil {
+        return nil, err
+    }
+
+    groups := make(map[string]*ansibleGroup)
+    hostvars := make(map[string]HostVars)
+
+    addToGroup := func(name string, host string) {
+        group, ok := groups[name]
+        if !ok {
+            group = &ansibleGroup{Hosts: make([]string, 0)}
+            groups[name] = group
+        }
+        group.Hosts = append(group.Hosts, host)
+    }
+
+    for _, server := range servers {
+        if !c.config.TagAllowed(server.Tags) {
+            continue
+        }
+
+        rules := c.config.MatchingRules(server.Name)
+        if len(rules) == 0 {
+            continue
+        }
+
+        for _, rule := range rules {
+            for _, group := range rule.Groups {
+                addToGroup(group, server.Name)
+            }
+        }
+        for _, tag := range server.Tags {
+            addToGroup(tag, server.Name)
+        }
+
+        hostvars[server.Name] = c.hostVarsFor(server, bastion)
+    }
+
+    result := make(Inventory, len(groups)+1)
+    for name, group := range groups {
+        result[name] = group
+    }
+    result["_meta"] = map[string]interface{}{"hostvars": hostvars}
+    return result, nil
+}
+
+//
+// Host returns the Ansible variables for a single server name. It is kept
+// for Ansible compatibility (Ansible skips --host once List returns
+// _meta.hostvars) and reads from the same cached pass as List.
+//
+func (c *Client) Host(ctx context.Context, name string) (HostVars, error) {
+    if len(c.config.MatchingRules(name)) == 0 {
+        return nil, fmt.Errorf("inventory: %w: %s does not match any configured rule", ErrServerNotFound, name)
+    }
+
+    servers, err := c.servers(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    bastion, err := c.findBastion(servers)
+    if err != nil {
+        return nil, err
+    }
+
+    server, err := c.findServer(servers, name)
+    if err != nil {
+        return nil, err
+    }
+
+    return c.hostVarsFor(server, bastion), nil
+}
+
+//
+// servers returns the merged, multi-zone server list, populating it from
+// the on-disk cache when fresh or from the Scaleway API otherwise.
+//
+func (c *Client) servers(ctx context.Context) ([]*instance.Server, error) {
+    path, err := c.cachePath()
+    if err != nil {
+        return c.listAllZones(ctx)
+    }
+
+    if !c.refreshCache {
+        if entry, ok := loadCache(path, c.cacheTTL); ok {
+            return entry.Servers, nil
+        }
+    }
+
+    return fetchWithCache(path, c.cacheTTL, c.refreshCache, func() ([]*instance.Server, error) {
+        return c.listAllZones(ctx)
+    })
+}
+
+//
+// listAllZones fans a ListServers call out across every configured zone in
+// parallel, retrying transient failures with backoff, and merges the results
+//
+func (c *Client) listAllZones(ctx context.Context) ([]*instance.Server, error) {
+
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    var servers []*instance.Server
+    var firstErr error
+
+    for _, zone := range c.zones {
+        wg.Add(1)
+        go func(zone scw.Zone) {
+            defer wg.Done()
+
+            var resp *instance.ListServersResponse
+            err := withRetry(ctx, func() error {
+                r, err := c.api.ListServers(&instance.ListServersRequest{
+                    Zone: zone,
+                }, scw.WithAllPages(), scw.WithContext(ctx))
+                if err != nil {
+                    return err
+                }
+                resp = r
+                return nil
+            })
+
+            mu.Lock()
+            defer mu.Unlock()
+            if err != nil {
+                if firstErr == nil {
+                    firstErr = fmt.Errorf("inventory: failed to list servers in zone %s: %w", zone, err)
+                }
+                return
+            }
+            servers = append(servers, resp.Servers...)
+        }(zone)
+    }
+    wg.Wait()
+
+    if firstErr != nil {
+        return nil, firstErr
+    }
+    return servers, nil
+}
+
+//
+// findServer looks a server up by name within an already-fetched list
+//
+func (c *Client) findServer(servers []*instance.Server, name string) (*instance.Server, error) {
+    for _, server := range servers {
+        if server.Name == name {
+            return server, nil
+        }
+    }
+    return nil, fmt.Errorf("inventory: %w: %s", ErrServerNotFound, name)
+}
+
+//
+// findBastion locates the bastion server matched by the config's
+// bastion.name_pattern within an already-fetched server list
+//
+func (c *Client) findBastion(servers []*instance.Server) (*instance.Server, error) {
+    for _, server := range servers {
+        if c.config.IsBastion(server.Name) {
+            return server, nil
+        }
+    }
+    return nil, fmt.Errorf("inventory: %w: no server matches bastion pattern %s", ErrServerNotFound, c.config.Bastion.NamePattern)
+}
+
+//
+// hostVarsFor computes every ansible_* / scw_* / vpn_ip variable for a
+// single server, given the already-resolved bastion
+//
+func (c *Client) hostVarsFor(server *instance.Server, bastion *instance.Server) HostVars {
+
+    result := make(HostVars)
+
+    result["ansible_python_interpreter"] = "/usr/bin/python3"
+    result["ansible_user"] = c.config.Bastion.SshUser
+
+    result["ansible_ssh_common_args"] = "-q -C -o ControlMaster=auto -o ControlPersist=5m -o ForwardAgent=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+
+    if server.ID == bastion.ID {
+        result["proxy_inet"] = "True"
+    } else if bastion.PublicIP != nil {
+        result["ansible_ssh_common_args"] = fmt.Sprintf("%s -o ProxyCommand=\"ssh -p %d -W %%h:%%p -q %s@%s -i %s\"",
+            result["ansible_ssh_common_args"], c.config.Bastion.SshPort, c.config.Bastion.SshUser, bastion.PublicIP.Address.String(), c.config.Bastion.SshKey)
+    }
+
+    if server.PublicIP != nil && server.PublicIP.Address.String() != "" {
+        result["ansible_host"] = server.PublicIP.Address.String()
+    } else if server.PrivateIP != nil {
+        result["ansible_host"] = *server.PrivateIP
+    }
+
+    result["scw_zone"] = server.Zone.String()
+    if region, err := server.Zone.Region(); err == nil {
+        result["scw_region"] = region.String()
+    }
+    result["scw_commercial_type"] = server.CommercialType
+    if server.Image != nil {
+        result["scw_image"] = server.Image.Name
+    }
+
+    if vpnIP := c.config.VpnIP(server.Name); vpnIP != "" {
+        result["vpn_ip"] = vpnIP
+    }
+
+    return result
+}
@@ -0,0 +1,225 @@
+package inventory
+
+//
+// Dependencies
+//
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/scaleway/scaleway-sdk-go/scw"
+)
+
+func TestClassifyError(t *testing.T) {
+    cases := []struct {
+        name       string
+        statusCode int
+        wantErr    error
+    }{
+        {"not found maps to ErrServerNotFound", http.StatusNotFound, ErrServerNotFound},
+        {"unauthorized maps to ErrAuth", http.StatusUnauthorized, ErrAuth},
+        {"forbidden maps to ErrAuth", http.StatusForbidden, ErrAuth},
+        {"server error maps to ErrTransient", http.StatusInternalServerError, ErrTransient},
+        {"bad gateway maps to ErrTransient", http.StatusBadGateway, ErrTransient},
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            respErr := &scw.ResponseError{StatusCode: tc.statusCode, Message: "boom"}
+            got := classifyError(respErr)
+            if !errors.Is(got, tc.wantErr) {
+                t.Fatalf("classifyError(%d) = %v, want wrapped %v", tc.statusCode, got, tc.wantErr)
+            }
+        })
+    }
+
+    t.Run("transport error maps to ErrTransient", func(t *testing.T) {
+        got := classifyError(fmt.Errorf("connection reset by peer"))
+        if !errors.Is(got, ErrTransient) {
+            t.Fatalf("classifyError(transport) = %v, want wrapped ErrTransient", got)
+        }
+    })
+}
+
+func TestWithRetry(t *testing.T) {
+    t.Run("succeeds without retrying on success", func(t *testing.T) {
+        calls := 0
+        err := withRetry(context.Background(), func() error {
+            calls++
+            return nil
+        })
+        if err != nil {
+            t.Fatalf("withRetry() error = %v, want nil", err)
+        }
+        if calls != 1 {
+            t.Fatalf("fn called %d times, want 1", calls)
+        }
+    })
+
+    t.Run("retries transient errors until success", func(t *testing.T) {
+        calls := 0
+        err := withRetry(context.Background(), func() error {
+            calls++
+            if calls < 3 {
+                return &scw.ResponseError{StatusCode: http.StatusServiceUnavailable}
+            }
+            return nil
+        })
+        if err != nil {
+            t.Fatalf("withRetry() error = %v, want nil", err)
+        }
+        if calls != 3 {
+            t.Fatalf("fn called %d times, want 3", calls)
+        }
+    })
+
+    t.Run("gives up after retryMaxAttempts", func(t *testing.T) {
+        calls := 0
+        err := withRetry(context.Background(), func() error {
+            calls++
+            return &scw.ResponseError{StatusCode: http.StatusServiceUnavailable}
+        })
+        if !errors.Is(err, ErrTransient) {
+            t.Fatalf("withRetry() error = %v, want wrapped ErrTransient", err)
+        }
+        if calls != retryMaxAttempts {
+            t.Fatalf("fn called %d times, want %d", calls, retryMaxAttempts)
+        }
+    })
+
+    t.Run("does not retry non-transient errors", func(t *testing.T) {
+        calls := 0
+        err := withRetry(context.Background(), func() error {
+            calls++
+            return &scw.ResponseError{StatusCode: http.StatusNotFound}
+        })
+        if !errors.Is(err, ErrServerNotFound) {
+            t.Fatalf("withRetry() error = %v, want wrapped ErrServerNotFound", err)
+        }
+        if calls != 1 {
+            t.Fatalf("fn called %d times, want 1 (no retry on non-transient error)", calls)
+        }
+    })
+}
+
+//
+// fakeListServersHandler mocks the subset of the Scaleway instance API that
+// Client.servers depends on: a single zones/{zone}/servers endpoint.
+//
+func fakeListServersHandler(t *testing.T, servers interface{}) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        if err := json.NewEncoder(w).Encode(map[string]interface{}{
+            "servers":     servers,
+            "total_count": 1,
+        }); err != nil {
+            t.Fatalf("failed to encode fake response: %s", err)
+        }
+    }
+}
+
+func TestClient_List(t *testing.T) {
+    fakeServers := []map[string]interface{}{
+        {
+            "id":              "11111111-1111-1111-1111-111111111111",
+            "name":            "proxy0",
+            "commercial_type": "DEV1-S",
+            "tags":            []string{"env:prod"},
+            "zone":            "fr-par-1",
+            "public_ip":       map[string]interface{}{"id": "ip-1", "address": "51.15.0.1"},
+            "image":           map[string]interface{}{"id": "img-1", "name": "ubuntu_focal"},
+        },
+        {
+            "id":              "22222222-2222-2222-2222-222222222222",
+            "name":            "master1",
+            "commercial_type": "DEV1-M",
+            "tags":            []string{"env:prod"},
+            "zone":            "fr-par-1",
+            "public_ip":       map[string]interface{}{"id": "ip-2", "address": "51.15.0.2"},
+            "image":           map[string]interface{}{"id": "img-1", "name": "ubuntu_focal"},
+        },
+    }
+
+    t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+    server := httptest.NewServer(fakeListServersHandler(t, fakeServers))
+    defer server.Close()
+
+    client, err := NewClient(ClientConfig{
+        Organization: "00000000-0000-0000-0000-000000000000",
+        AccessKey:    "SCW11111111111111111",
+        Token:        "11111111-1111-1111-1111-111111111111",
+        Zones:        []scw.Zone{scw.Zone("fr-par-1")},
+        APIURL:       server.URL,
+        RefreshCache: true,
+    })
+    if err != nil {
+        t.Fatalf("NewClient() error = %v", err)
+    }
+
+    inv, err := client.List(context.Background())
+    if err != nil {
+        t.Fatalf("List() error = %v", err)
+    }
+
+    proxyGroup, ok := inv["proxy"].(*ansibleGroup)
+    if !ok || len(proxyGroup.Hosts) != 1 || proxyGroup.Hosts[0] != "proxy0" {
+        t.Fatalf("inv[\"proxy\"] = %#v, want a group containing proxy0", inv["proxy"])
+    }
+
+    meta, ok := inv["_meta"].(map[string]interface{})
+    if !ok {
+        t.Fatalf("inv[\"_meta\"] missing or wrong type: %#v", inv["_meta"])
+    }
+    hostvars, ok := meta["hostvars"].(map[string]HostVars)
+    if !ok {
+        t.Fatalf("_meta.hostvars missing or wrong type: %#v", meta["hostvars"])
+    }
+    if hostvars["master1"]["vpn_ip"] != "192.168.66.21" {
+        t.Fatalf("master1 vpn_ip = %q, want 192.168.66.21", hostvars["master1"]["vpn_ip"])
+    }
+    if hostvars["proxy0"]["proxy_inet"] != "True" {
+        t.Fatalf("proxy0 proxy_inet = %q, want True", hostvars["proxy0"]["proxy_inet"])
+    }
+}
+
+func TestClient_Host_NotFound(t *testing.T) {
+    t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+    fakeServers := []map[string]interface{}{
+        {
+            "id":              "11111111-1111-1111-1111-111111111111",
+            "name":            "proxy0",
+            "commercial_type": "DEV1-S",
+            "tags":            []string{"env:prod"},
+            "zone":            "fr-par-1",
+            "public_ip":       map[string]interface{}{"id": "ip-1", "address": "51.15.0.1"},
+            "image":           map[string]interface{}{"id": "img-1", "name": "ubuntu_focal"},
+        },
+    }
+
+    server := httptest.NewServer(fakeListServersHandler(t, fakeServers))
+    defer server.Close()
+
+    client, err := NewClient(ClientConfig{
+        Organization: "00000000-0000-0000-0000-000000000000",
+        AccessKey:    "SCW11111111111111111",
+        Token:        "11111111-1111-1111-1111-111111111111",
+        Zones:        []scw.Zone{scw.Zone("fr-par-1")},
+        APIURL:       server.URL,
+        RefreshCache: true,
+    })
+    if err != nil {
+        t.Fatalf("NewClient() error = %v", err)
+    }
+
+    _, err = client.Host(context.Background(), "master1")
+    if !errors.Is(err, ErrServerNotFound) {
+        t.Fatalf("Host() error = %v, want wrapped ErrServerNotFound", err)
+    }
+}
@@ -0,0 +1,213 @@
+package inventory
+
+//
+// Dependencies
+//
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v2"
+)
+
+//
+// Global constants
+//
+const (
+    defaultSshUser = "root"
+    defaultSshKey  = "~/.ssh/scaleway.pem"
+    defaultSshPort = 22
+)
+
+//
+// Config describes how to filter, group and reach a fleet. It is loaded
+// from SCW_INVENTORY_CONFIG (or --config) as YAML or JSON and replaces the
+// previously hardcoded name regexp / VPN-IP mapping.
+//
+type Config struct {
+    Rules      []NameRule `yaml:"rules" json:"rules"`
+    TagFilters TagFilters `yaml:"tag_filters" json:"tag_filters"`
+    Bastion    BastionConfig `yaml:"bastion" json:"bastion"`
+}
+
+//
+// NameRule matches server names against Pattern and assigns them to Groups.
+// Pattern may carry a named capture group (index) consumed by VpnIpTemplate,
+// e.g. pattern `^master(?P<index>[0-9]+)$` with template `192.168.66.2{{index}}`.
+//
+type NameRule struct {
+    Pattern       string `yaml:"pattern" json:"pattern"`
+    Groups        []string `yaml:"groups" json:"groups"`
+    VpnIpTemplate string `yaml:"vpn_ip_template" json:"vpn_ip_template"`
+    compiled      *regexp.Regexp
+}
+
+//
+// TagFilters narrows the fleet down by Scaleway tag before grouping.
+//
+type TagFilters struct {
+    Include []string `yaml:"include" json:"include"`
+    Exclude []string `yaml:"exclude" json:"exclude"`
+}
+
+//
+// BastionConfig selects the jump host used to build the ProxyCommand and
+// replaces the hardcoded "proxy0" lookup.
+//
+type BastionConfig struct {
+    NamePattern string `yaml:"name_pattern" json:"name_pattern"`
+    SshUser     string `yaml:"ssh_user" json:"ssh_user"`
+    SshKey      string `yaml:"ssh_key" json:"ssh_key"`
+    SshPort     int `yaml:"ssh_port" json:"ssh_port"`
+    compiled    *regexp.Regexp
+}
+
+//
+// DefaultConfig reproduces the behaviour this tool had before config
+// support: proxyN/masterN/workerN servers grouped by role, VPN IPs in the
+// 192.168.66.0/24 range, and proxy0 as the bastion
+//
+func DefaultConfig() *Config {
+    return &Config{
+        Rules: []NameRule{
+            {Pattern: "^proxy(?P<index>[0-9]+)$", Groups: []string{"proxy"}, VpnIpTemplate: "192.168.66.1{{index}}"},
+            {Pattern: "^master(?P<index>[0-9]+)$", Groups: []string{"master"}, VpnIpTemplate: "192.168.66.2{{index}}"},
+            {Pattern: "^worker(?P<index>[0-9]+)$", Groups: []string{"worker"}, VpnIpTemplate: "192.168.66.3{{index}}"},
+        },
+        Bastion: BastionConfig{
+            NamePattern: "^proxy0$",
+            SshUser:     defaultSshUser,
+            SshKey:      defaultSshKey,
+            SshPort:     defaultSshPort,
+        },
+    }
+}
+
+//
+// LoadConfig reads a config file and unmarshals it as JSON or YAML
+// depending on its extension, then compiles its regexps
+//
+func LoadConfig(path string) (*Config, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("inventory: failed to read config %s: %w", path, err)
+    }
+
+    cfg := &Config{}
+    if strings.ToLower(filepath.Ext(path)) == ".json" {
+        if err := json.Unmarshal(data, cfg); err != nil {
+            return nil, fmt.Errorf("inventory: failed to parse config %s: %w", path, err)
+        }
+    } else {
+        if err := yaml.Unmarshal(data, cfg); err != nil {
+            return nil, fmt.Errorf("inventory: failed to parse config %s: %w", path, err)
+        }
+    }
+
+    if err := compileConfig(cfg); err != nil {
+        return nil, fmt.Errorf("inventory: invalid config %s: %w", path, err)
+    }
+    return cfg, nil
+}
+
+//
+// compileConfig compiles every regexp in the config, applies the bastion
+// SSH defaults and fills in a default bastion pattern if none given
+//
+func compileConfig(cfg *Config) error {
+    for i := range cfg.Rules {
+        re, err := regexp.Compile(cfg.Rules[i].Pattern)
+        if err != nil {
+            return err
+        }
+        cfg.Rules[i].compiled = re
+    }
+
+    if cfg.Bastion.NamePattern == "" {
+        cfg.Bastion.NamePattern = "^proxy0$"
+    }
+    re, err := regexp.Compile(cfg.Bastion.NamePattern)
+    if err != nil {
+        return err
+    }
+    cfg.Bastion.compiled = re
+
+    if cfg.Bastion.SshUser == "" {
+        cfg.Bastion.SshUser = defaultSshUser
+    }
+    if cfg.Bastion.SshKey == "" {
+        cfg.Bastion.SshKey = defaultSshKey
+    }
+    if cfg.Bastion.SshPort == 0 {
+        cfg.Bastion.SshPort = defaultSshPort
+    }
+    return nil
+}
+
+//
+// MatchingRules returns every configured NameRule whose pattern matches name
+//
+func (c *Config) MatchingRules(name string) []*NameRule {
+    var matched []*NameRule
+    for i := range c.Rules {
+        rule := &c.Rules[i]
+        if rule.compiled.MatchString(name) {
+            matched = append(matched, rule)
+        }
+    }
+    return matched
+}
+
+//
+// VpnIP resolves the vpn_ip_template of the first matching rule that has both
+// a template and a named "index" capture group in its pattern
+//
+func (c *Config) VpnIP(name string) string {
+    for _, rule := range c.MatchingRules(name) {
+        if rule.VpnIpTemplate == "" {
+            continue
+        }
+        match := rule.compiled.FindStringSubmatch(name)
+        for i, group := range rule.compiled.SubexpNames() {
+            if group == "index" && i < len(match) {
+                return strings.Replace(rule.VpnIpTemplate, "{{index}}", match[i], -1)
+            }
+        }
+    }
+    return ""
+}
+
+//
+// TagAllowed applies the config's include/exclude tag filters
+//
+func (c *Config) TagAllowed(tags []string) bool {
+    if len(c.TagFilters.Include) > 0 && !anyTagMatches(tags, c.TagFilters.Include) {
+        return false
+    }
+    if len(c.TagFilters.Exclude) > 0 && anyTagMatches(tags, c.TagFilters.Exclude) {
+        return false
+    }
+    return true
+}
+
+//
+// IsBastion reports whether name matches the configured bastion pattern
+//
+func (c *Config) IsBastion(name string) bool {
+    return c.Bastion.compiled.MatchString(name)
+}
+
+func anyTagMatches(tags []string, candidates []string) bool {
+    for _, tag := range tags {
+        for _, candidate := range candidates {
+            if tag == candidate {
+                return true
+            }
+        }
+    }
+    return false
+}
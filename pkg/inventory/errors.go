@@ -0,0 +1,20 @@
+package inventory
+
+import "errors"
+
+// Sentinel errors returned by Client. Callers should use errors.Is to test
+// for them, since they are always wrapped with request-specific context.
+var (
+    // ErrServerNotFound is returned when a requested server name has no
+    // match across any configured zone.
+    ErrServerNotFound = errors.New("inventory: server not found")
+
+    // ErrAuth is returned when the Scaleway API rejects the configured
+    // organization/token (401/403).
+    ErrAuth = errors.New("inventory: authentication failed")
+
+    // ErrTransient is returned when a request failed in a way that is
+    // expected to succeed on retry (5xx responses, transport errors). It is
+    // only returned to the caller once the retry budget is exhausted.
+    ErrTransient = errors.New("inventory: transient error")
+)
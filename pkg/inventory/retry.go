@@ -0,0 +1,84 @@
+package inventory
+
+//
+// Dependencies
+//
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/scaleway/scaleway-sdk-go/scw"
+)
+
+//
+// Global constants
+//
+const (
+    retryMaxAttempts = 5
+    retryBaseDelay   = 200 * time.Millisecond
+)
+
+//
+// withRetry runs fn, retrying with exponential backoff while the error it
+// returns classifies as transient (5xx / transport errors), up to
+// retryMaxAttempts. Non-transient errors (auth, not-found, ...) return
+// immediately without consuming the retry budget.
+//
+func withRetry(ctx context.Context, fn func() error) error {
+    var lastErr error
+    delay := retryBaseDelay
+
+    for attempt := 0; attempt < retryMaxAttempts; attempt++ {
+        err := fn()
+        if err == nil {
+            return nil
+        }
+
+        classified := classifyError(err)
+        if !errors.Is(classified, ErrTransient) {
+            return classified
+        }
+        lastErr = classified
+
+        if attempt == retryMaxAttempts-1 {
+            break
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(delay):
+        }
+        delay *= 2
+    }
+    return lastErr
+}
+
+//
+// classifyError maps a raw Scaleway SDK / transport error onto our typed
+// sentinel errors so callers can use errors.Is instead of string matching
+//
+func classifyError(err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var respErr *scw.ResponseError
+    if errors.As(err, &respErr) {
+        switch {
+        case respErr.StatusCode == http.StatusNotFound:
+            return fmt.Errorf("%w: %s", ErrServerNotFound, respErr.Error())
+        case respErr.StatusCode == http.StatusUnauthorized, respErr.StatusCode == http.StatusForbidden:
+            return fmt.Errorf("%w: %s", ErrAuth, respErr.Error())
+        case respErr.StatusCode >= http.StatusInternalServerError:
+            return fmt.Errorf("%w: %s", ErrTransient, respErr.Error())
+        }
+        return err
+    }
+
+    // Anything that isn't a well-formed API response (connection reset,
+    // timeout, DNS failure, ...) is assumed transient
+    return fmt.Errorf("%w: %s", ErrTransient, err)
+}
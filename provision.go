@@ -0,0 +1,292 @@
+package main
+
+//
+// Dependencies
+//
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/scaleway/scaleway-sdk-go/api/instance/v1"
+    "github.com/scaleway/scaleway-sdk-go/api/marketplace/v2"
+    "github.com/scaleway/scaleway-sdk-go/scw"
+)
+
+//
+// Global constants
+//
+const (
+    provisionWorkerPoolSize = 16
+    destroyPollInterval     = 5 * time.Second
+    destroyPollTimeout      = 5 * time.Minute
+)
+
+//
+// ManifestEntry is one line of a spawn/run/destroy manifest:
+// "<zone> <commercial_type> <image_label> <name>"
+//
+type ManifestEntry struct {
+    Zone           scw.Zone
+    CommercialType string
+    ImageLabel     string
+    Name           string
+}
+
+//
+// parseManifest reads a plain-text manifest, skipping blank lines and
+// "#"-prefixed comments
+//
+func parseManifest(path string) ([]ManifestEntry, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var entries []ManifestEntry
+    scanner := bufio.NewScanner(f)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        fields := strings.Fields(line)
+        if len(fields) != 4 {
+            return nil, fmt.Errorf("manifest line %d: expected \"<zone> <commercial_type> <image_label> <name>\", got %q", lineNo, line)
+        }
+        entries = append(entries, ManifestEntry{
+            Zone:           scw.Zone(fields[0]),
+            CommercialType: fields[1],
+            ImageLabel:     fields[2],
+            Name:           fields[3],
+        })
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+//
+// runProvisioning fans the manifest out across a bounded worker pool and
+// aggregates per-instance errors instead of exiting on the first failure
+//
+func runProvisioning(action string, manifestPath string) error {
+
+    entries, err := parseManifest(manifestPath)
+    if err != nil {
+        return fmt.Errorf("failed to read manifest: %s", err)
+    }
+
+    sem := make(chan struct{}, provisionWorkerPoolSize)
+    var wg sync.WaitGroup
+    errs := make([]error, len(entries))
+
+    for i, entry := range entries {
+        wg.Add(1)
+        sem <- struct{}{}
+        go func(i int, entry ManifestEntry) {
+            defer wg.Done()
+            defer func() { <-sem }()
+
+            var err error
+            switch action {
+            case "spawn":
+                err = spawnInstance(entry)
+            case "run":
+                err = runInstance(entry)
+            case "destroy":
+                err = destroyInstance(entry)
+            }
+            if err != nil {
+                errs[i] = fmt.Errorf("%s: %s", entry.Name, err)
+            } else {
+                l.Printf("%s %s %s: ok", MSG_PREFIX, action, entry.Name)
+            }
+        }(i, entry)
+    }
+    wg.Wait()
+
+    return aggregateErrors(errs)
+}
+
+//
+// aggregateErrors folds the per-instance errors into a single error,
+// or nil if every instance succeeded
+//
+func aggregateErrors(errs []error) error {
+    var messages []string
+    for _, err := range errs {
+        if err != nil {
+            messages = append(messages, err.Error())
+        }
+    }
+    if len(messages) == 0 {
+        return nil
+    }
+    return fmt.Errorf("%d/%d instances failed:\n%s", len(messages), len(errs), strings.Join(messages, "\n"))
+}
+
+//
+// spawnInstance creates the manifest's instance if it doesn't already exist
+// in that zone, resolves the image label via the marketplace API and
+// attaches a freshly reserved IP. Idempotent: an existing instance with the
+// same name is left untouched.
+//
+func spawnInstance(entry ManifestEntry) error {
+
+    existing, err := findServerInZone(entry.Zone, entry.Name)
+    if err != nil {
+        return fmt.Errorf("failed to check for an existing instance: %s", err)
+    }
+    if existing != nil {
+        l.Printf("%s instance %s already exists in %s, skipping creation", MSG_PREFIX, entry.Name, entry.Zone)
+        return nil
+    }
+
+    imageID, err := resolveImageID(entry.Zone, entry.CommercialType, entry.ImageLabel)
+    if err != nil {
+        return fmt.Errorf("failed to resolve image %q: %s", entry.ImageLabel, err)
+    }
+
+    ip, err := reserveIP(entry.Zone)
+    if err != nil {
+        return fmt.Errorf("failed to reserve an IP: %s", err)
+    }
+
+    _, err = scwApi.CreateServer(&instance.CreateServerRequest{
+        Zone:           entry.Zone,
+        Name:           entry.Name,
+        CommercialType: entry.CommercialType,
+        Image:          &imageID,
+        PublicIP:       &ip.ID,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to create instance: %s", err)
+    }
+    return nil
+}
+
+//
+// runInstance powers the manifest's instance on, creating nothing
+//
+func runInstance(entry ManifestEntry) error {
+
+    server, err := findServerInZone(entry.Zone, entry.Name)
+    if err != nil {
+        return fmt.Errorf("failed to look up instance: %s", err)
+    }
+    if server == nil {
+        return fmt.Errorf("no instance named %s in zone %s, run spawn first", entry.Name, entry.Zone)
+    }
+
+    _, err = scwApi.ServerAction(&instance.ServerActionRequest{
+        Zone:     entry.Zone,
+        ServerID: server.ID,
+        Action:   instance.ServerActionPoweron,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to power on instance: %s", err)
+    }
+    return nil
+}
+
+//
+// destroyInstance terminates the manifest's instance and polls until it is
+// stopped or gone. A missing instance is treated as already destroyed.
+//
+func destroyInstance(entry ManifestEntry) error {
+
+    server, err := findServerInZone(entry.Zone, entry.Name)
+    if err != nil {
+        return fmt.Errorf("failed to look up instance: %s", err)
+    }
+    if server == nil {
+        l.Printf("%s instance %s already gone from %s, nothing to destroy", MSG_PREFIX, entry.Name, entry.Zone)
+        return nil
+    }
+
+    _, err = scwApi.ServerAction(&instance.ServerActionRequest{
+        Zone:     entry.Zone,
+        ServerID: server.ID,
+        Action:   instance.ServerActionTerminate,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to terminate instance: %s", err)
+    }
+
+    deadline := time.Now().Add(destroyPollTimeout)
+    for time.Now().Before(deadline) {
+        server, err := findServerInZone(entry.Zone, entry.Name)
+        if err != nil {
+            return fmt.Errorf("failed to poll instance state: %s", err)
+        }
+        if server == nil || server.State == instance.ServerStateStopped {
+            return nil
+        }
+        time.Sleep(destroyPollInterval)
+    }
+    return fmt.Errorf("timed out after %s waiting for instance to stop/delete", destroyPollTimeout)
+}
+
+//
+// findServerInZone looks up an instance by name within a single zone,
+// returning nil, nil if it doesn't exist
+//
+func findServerInZone(zone scw.Zone, name string) (*instance.Server, error) {
+    resp, err := scwApi.ListServers(&instance.ListServersRequest{
+        Zone: zone,
+        Name: &name,
+    }, scw.WithAllPages())
+    if err != nil {
+        return nil, err
+    }
+    for _, server := range resp.Servers {
+        if server.Name == name {
+            return server, nil
+        }
+    }
+    return nil, nil
+}
+
+//
+// resolveImageID looks up the local image ID for a marketplace label in a
+// given zone, picking the variant compatible with commercialType
+//
+func resolveImageID(zone scw.Zone, commercialType string, label string) (string, error) {
+    marketplaceApi := marketplace.NewAPI(scwClient)
+    resp, err := marketplaceApi.ListLocalImages(&marketplace.ListLocalImagesRequest{
+        Zone:       &zone,
+        ImageLabel: &label,
+    }, scw.WithAllPages())
+    if err != nil {
+        return "", err
+    }
+    for _, localImage := range resp.LocalImages {
+        for _, compatible := range localImage.CompatibleCommercialTypes {
+            if compatible == commercialType {
+                return localImage.ID, nil
+            }
+        }
+    }
+    return "", fmt.Errorf("no local image for label %q compatible with commercial type %q in zone %s", label, commercialType, zone)
+}
+
+//
+// reserveIP creates a fresh reserved IP in the given zone
+//
+func reserveIP(zone scw.Zone) (*instance.IP, error) {
+    resp, err := scwApi.CreateIP(&instance.CreateIPRequest{
+        Zone: zone,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return resp.IP, nil
+}